@@ -0,0 +1,40 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// protoc-gen-go-grpc is a plugin for the Google protocol buffer compiler to
+// generate Go code for gRPC service definitions. Install it by building this
+// program and making it accessible within your PATH under the name:
+//
+//	protoc-gen-go-grpc
+//
+// The 'go-grpc_out' flag of protoc should be used to specify the output
+// directory. protoc-gen-go-grpc does not emit message types; run
+// protoc-gen-go alongside it (or use 'go_out') to generate those.
+package main
+
+import (
+	"flag"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/internal/gengogrpc"
+)
+
+func main() {
+	var flags flag.FlagSet
+	plugins := flags.String("plugins", "", "deprecated: protoc-gen-go-grpc does not use this parameter")
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		if err := gengogrpc.CheckDeprecatedPluginsParam(*plugins); err != nil {
+			return err
+		}
+		gen.SupportedFeatures = gengogrpc.SupportedFeatures
+		for _, f := range gen.Files {
+			if f.Generate {
+				gengogrpc.GenerateFile(gen, f)
+			}
+		}
+		return nil
+	})
+}
@@ -15,19 +15,55 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/internal/gengogrpc"
 )
 
+// grpcMain runs the protoc-gen-go-grpc plugin logic. It lets this same test
+// binary stand in for the separate cmd/protoc-gen-go-grpc binary so that
+// TestGolden can exercise both plugins without shelling out to two different
+// compiled programs.
+func grpcMain() {
+	var flags flag.FlagSet
+	plugins := flags.String("plugins", "", "deprecated: protoc-gen-go-grpc does not use this parameter")
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		if err := gengogrpc.CheckDeprecatedPluginsParam(*plugins); err != nil {
+			return err
+		}
+		gen.SupportedFeatures = gengogrpc.SupportedFeatures
+		for _, f := range gen.Files {
+			if f.Generate {
+				gengogrpc.GenerateFile(gen, f)
+			}
+		}
+		return nil
+	})
+}
+
 // Set --regenerate to regenerate the golden files.
 var regenerate = flag.Bool("regenerate", false, "regenerate golden files")
 
 // When the environment variable RUN_AS_PROTOC_GEN_GO is set, we skip running
 // tests and instead act as protoc-gen-go. This allows the test binary to
 // pass itself to protoc.
+//
+// Similarly, RUN_AS_PROTOC_GEN_GO_GRPC makes the test binary act as
+// protoc-gen-go-grpc, the standalone gRPC service-stub plugin. Generating
+// gRPC stubs no longer requires the deprecated 'plugins=grpc' parameter to
+// this plugin; TestGolden now runs the two plugins side by side, the same
+// way downstream tooling invokes them.
 func init() {
 	if os.Getenv("RUN_AS_PROTOC_GEN_GO") != "" {
 		main()
 		os.Exit(0)
 	}
+	if os.Getenv("RUN_AS_PROTOC_GEN_GO_GRPC") != "" {
+		grpcMain()
+		os.Exit(0)
+	}
 }
 
 func TestGolden(t *testing.T) {
@@ -57,11 +93,20 @@ func TestGolden(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Compile each package, using this binary as protoc-gen-go.
+	// Compile each package, using this binary as both protoc-gen-go (message
+	// code) and protoc-gen-go-grpc (service stubs). These used to be a single
+	// invocation gated by the 'plugins=grpc' parameter; that parameter is now
+	// deprecated in favor of running the two plugins side by side, so the
+	// golden files for *_grpc.pb.go are produced and checked independently of
+	// the message code.
 	for _, sources := range packages {
-		args := []string{"-Itestdata", "--go_out=plugins=grpc,paths=source_relative:" + workdir}
+		args := []string{"-Itestdata", "--go_out=paths=source_relative:" + workdir}
 		args = append(args, sources...)
 		protoc(t, args)
+
+		args = []string{"-Itestdata", "--go-grpc_out=paths=source_relative:" + workdir}
+		args = append(args, sources...)
+		protocGRPC(t, args)
 	}
 
 	// Compare each generated file to the golden version.
@@ -135,6 +180,26 @@ func protoc(t *testing.T, args []string) {
 	}
 }
 
+// protocGRPC runs protoc with this binary acting as protoc-gen-go-grpc,
+// the standalone plugin that emits *_grpc.pb.go service stubs.
+func protocGRPC(t *testing.T, args []string) {
+	cmd := exec.Command("protoc", "--plugin=protoc-gen-go-grpc="+os.Args[0])
+	cmd.Args = append(cmd.Args, args...)
+	// We set RUN_AS_PROTOC_GEN_GO_GRPC to indicate that the subprocess should
+	// act as the gRPC stub generator rather than a test.
+	cmd.Env = append(os.Environ(), "RUN_AS_PROTOC_GEN_GO_GRPC=1")
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 || err != nil {
+		t.Log("RUNNING: ", strings.Join(cmd.Args, " "))
+	}
+	if len(out) > 0 {
+		t.Log(string(out))
+	}
+	if err != nil {
+		t.Fatalf("protoc: %v", err)
+	}
+}
+
 func hasReleaseTag(want string) bool {
 	for _, tag := range build.Default.ReleaseTags {
 		if tag == want {
@@ -142,4 +207,4 @@ func hasReleaseTag(want string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
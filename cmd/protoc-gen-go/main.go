@@ -0,0 +1,41 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// protoc-gen-go is a plugin for the Google protocol buffer compiler to
+// generate Go code. Install it by building this program and making it
+// accessible within your PATH with the name:
+//
+//	protoc-gen-go
+//
+// The 'go_out' flag of protoc should be used to specify the output
+// directory. protoc-gen-go does not generate gRPC service stubs; run
+// protoc-gen-go-grpc alongside it (or use 'go-grpc_out') to generate those.
+package main
+
+import (
+	"flag"
+
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/internal/gengogrpc"
+)
+
+func main() {
+	var flags flag.FlagSet
+	plugins := flags.String("plugins", "", "deprecated: inline gRPC generation via plugins=grpc is no longer supported")
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		if err := gengogrpc.CheckDeprecatedPluginsParam(*plugins); err != nil {
+			return err
+		}
+		gen.SupportedFeatures = gengo.SupportedFeatures
+		for _, f := range gen.Files {
+			if f.Generate {
+				gengo.GenerateFile(gen, f)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,206 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protojson_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/internal/testprotos/conformance"
+	"google.golang.org/protobuf/proto"
+)
+
+// When RUN_AS_CONFORMANCE is set, act as the target of the upstream protobuf
+// conformance-test-runner: read length-prefixed ConformanceRequest messages
+// from stdin and write length-prefixed ConformanceResponse messages to
+// stdout. This lets TestConformance shell out to conformance-test-runner
+// with this test binary as the target, the same way TestGolden's init
+// trampoline lets cmd/protoc-gen-go's test binary stand in for protoc.
+func init() {
+	if os.Getenv("RUN_AS_CONFORMANCE") != "" {
+		if err := runConformance(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+func runConformance(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		req, err := readConformanceRequest(br)
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeConformanceResponse(bw, handleConformanceRequest(req)); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func readConformanceRequest(r io.Reader) (*conformance.ConformanceRequest, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.LittleEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	req := &conformance.ConformanceRequest{}
+	if err := proto.Unmarshal(buf, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func writeConformanceResponse(w io.Writer, resp *conformance.ConformanceResponse) error {
+	buf, err := proto.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	var lenBuf [4]byte
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(buf)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// newConformanceMessage returns a new, empty instance of the message named by
+// a ConformanceRequest's message_type field, so that proto2 requests exercise
+// proto2 semantics (e.g. required fields, no implicit presence on scalars)
+// instead of silently being parsed as the proto3 message.
+func newConformanceMessage(messageType string) (proto.Message, bool) {
+	switch messageType {
+	case "protobuf_test_messages.proto3.TestAllTypesProto3":
+		return &conformance.TestAllTypesProto3{}, true
+	case "protobuf_test_messages.proto2.TestAllTypesProto2":
+		return &conformance.TestAllTypesProto2{}, true
+	default:
+		return nil, false
+	}
+}
+
+// handleConformanceRequest parses req's payload, re-encodes it in the
+// requested output format, and reports the outcome the way the conformance
+// protocol expects: a populated payload on success, or one of parse_error,
+// serialize_error, runtime_error, skipped on failure.
+func handleConformanceRequest(req *conformance.ConformanceRequest) *conformance.ConformanceResponse {
+	msg, ok := newConformanceMessage(req.GetMessageType())
+	if !ok {
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_Skipped{Skipped: "unsupported message_type: " + req.GetMessageType()},
+		}
+	}
+
+	switch p := req.Payload.(type) {
+	case *conformance.ConformanceRequest_JsonPayload:
+		unmarshal := protojson.UnmarshalOptions{
+			DiscardUnknown: req.GetTestCategory() == conformance.TestCategory_JSON_IGNORE_UNKNOWN_PARSING_TEST,
+		}
+		if err := unmarshal.Unmarshal([]byte(p.JsonPayload), msg); err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_ParseError{ParseError: err.Error()},
+			}
+		}
+	case *conformance.ConformanceRequest_ProtobufPayload:
+		if err := proto.Unmarshal(p.ProtobufPayload, msg); err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_ParseError{ParseError: err.Error()},
+			}
+		}
+	case *conformance.ConformanceRequest_TextPayload:
+		if err := prototext.Unmarshal([]byte(p.TextPayload), msg); err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_ParseError{ParseError: err.Error()},
+			}
+		}
+	default:
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_Skipped{Skipped: "unsupported input format"},
+		}
+	}
+
+	switch req.RequestedOutputFormat {
+	case conformance.WireFormat_JSON:
+		out, err := protojson.MarshalOptions{AllowPartial: false}.Marshal(msg)
+		if err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_SerializeError{SerializeError: err.Error()},
+			}
+		}
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_JsonPayload{JsonPayload: string(out)},
+		}
+	case conformance.WireFormat_PROTOBUF:
+		out, err := proto.MarshalOptions{AllowPartial: false}.Marshal(msg)
+		if err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_SerializeError{SerializeError: err.Error()},
+			}
+		}
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_ProtobufPayload{ProtobufPayload: out},
+		}
+	case conformance.WireFormat_TEXT_FORMAT:
+		out, err := prototext.MarshalOptions{AllowPartial: false}.Marshal(msg)
+		if err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_SerializeError{SerializeError: err.Error()},
+			}
+		}
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_TextPayload{TextPayload: string(out)},
+		}
+	default:
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_Skipped{Skipped: "unsupported output format"},
+		}
+	}
+}
+
+// TestConformance runs protojson against the upstream protobuf conformance
+// test suite. Unlike TestGolden, which only diffs generated Go code, this
+// catches JSON-shape regressions - Any resolution, NaN/Inf, 64-bit-as-string,
+// enum aliases - that golden diffs can never see. Known-failing cases are
+// checked into testdata/conformance_failing.txt so that fixing one is a
+// visible diff rather than a silent newly-passing test.
+func TestConformance(t *testing.T) {
+	runner, err := exec.LookPath("conformance-test-runner")
+	if err != nil {
+		t.Skip("conformance-test-runner not found in PATH; skipping")
+	}
+
+	failureList := filepath.Join("testdata", "conformance_failing.txt")
+	cmd := exec.Command(runner,
+		"--failure_list", failureList,
+		os.Args[0])
+	cmd.Env = append(os.Environ(), "RUN_AS_CONFORMANCE=1")
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Log(string(out))
+	}
+	if err != nil {
+		t.Fatalf("conformance-test-runner: %v", err)
+	}
+}
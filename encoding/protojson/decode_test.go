@@ -0,0 +1,66 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protojson_test
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TestUnmarshalersRoundTrip round-trips the Unix-epoch-milliseconds
+// representation installed by TestMarshalersOverrideTimestamp back through
+// the symmetric UnmarshalOptions.Unmarshalers hook, demonstrating that a
+// message rendered by a registered Marshalers entry can be parsed back by a
+// registered Unmarshalers entry.
+func TestUnmarshalersRoundTrip(t *testing.T) {
+	want := timestamppb.New(time.Unix(1257894000, 0))
+
+	marshalOpts := protojson.MarshalOptions{
+		Marshalers: map[protoreflect.FullName]func(protojson.MarshalOptions, protoreflect.Message, io.Writer) error{
+			"google.protobuf.Timestamp": func(_ protojson.MarshalOptions, m protoreflect.Message, w io.Writer) error {
+				fields := m.Descriptor().Fields()
+				seconds := m.Get(fields.ByName("seconds")).Int()
+				nanos := m.Get(fields.ByName("nanos")).Int()
+				millis := seconds*1000 + nanos/1e6
+				_, err := io.WriteString(w, strconv.FormatInt(millis, 10))
+				return err
+			},
+		},
+	}
+	b, err := marshalOpts.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() returned error: %v", err)
+	}
+
+	unmarshalOpts := protojson.UnmarshalOptions{
+		Unmarshalers: map[protoreflect.FullName]func(protojson.UnmarshalOptions, []byte, protoreflect.Message) error{
+			"google.protobuf.Timestamp": func(_ protojson.UnmarshalOptions, raw []byte, m protoreflect.Message) error {
+				millis, err := strconv.ParseInt(string(raw), 10, 64)
+				if err != nil {
+					return err
+				}
+				fields := m.Descriptor().Fields()
+				m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(millis/1000))
+				m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(millis%1000*1e6)))
+				return nil
+			},
+		},
+	}
+	got := &timestamppb.Timestamp{}
+	if err := unmarshalOpts.Unmarshal(b, got); err != nil {
+		t.Fatalf("Unmarshal() returned error: %v", err)
+	}
+
+	if !proto.Equal(got, want) {
+		t.Errorf("Unmarshal() round-trip = %v, want %v", got, want)
+	}
+}
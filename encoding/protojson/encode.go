@@ -5,9 +5,12 @@
 package protojson
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
 
 	"google.golang.org/protobuf/internal/encoding/json"
 	"google.golang.org/protobuf/internal/encoding/messageset"
@@ -21,7 +24,8 @@ import (
 
 // Marshal writes the given proto.Message in JSON format using default options.
 // Do not depend on the output being stable. It may change over time across
-// different versions of the program.
+// different versions of the program. Set MarshalOptions.Deterministic for a
+// defined stability contract.
 func Marshal(m proto.Message) ([]byte, error) {
 	return MarshalOptions{}.Marshal(m)
 }
@@ -36,6 +40,22 @@ type MarshalOptions struct {
 	// Marshal will return error if there are any missing required fields.
 	AllowPartial bool
 
+	// Deterministic specifies whether to produce byte-stable output. Unlike
+	// the default output, which may vary across versions of this module as
+	// internal iteration order changes, Deterministic output is guaranteed
+	// stable for a given combination of message value, schema, and the other
+	// MarshalOptions fields: fields are emitted in field-number order rather
+	// than declaration order, Indent is forced to "", and extension keys and
+	// map entries (already sorted unconditionally) remain sorted. This is
+	// useful for signing or hashing JSON payloads (JWS-style detached
+	// signatures, content-addressed storage) where callers would otherwise
+	// have to post-process protojson's output through a separate
+	// canonicalizer. Deterministic cannot be combined with EmitUnpopulated,
+	// since whether a field's unpopulated default is emitted can depend on
+	// details - such as a field being added in a newer schema version - that
+	// are not stable inputs to canonicalization.
+	Deterministic bool
+
 	// UseProtoNames uses proto field name instead of lowerCamelCase name in JSON
 	// field names.
 	UseProtoNames bool
@@ -70,16 +90,67 @@ type MarshalOptions struct {
 		protoregistry.ExtensionTypeResolver
 		protoregistry.MessageTypeResolver
 	}
+
+	// HTTPBodyContentType overrides the content_type used when marshaling a
+	// google.api.HttpBody message whose content_type field is unset. This is
+	// useful when the content type is only known from surrounding HTTP
+	// context (e.g. a gRPC-transcoded response's Content-Type header) rather
+	// than from the message itself.
+	HTTPBodyContentType string
+
+	// Marshalers overrides the JSON representation of specific message
+	// types, keyed by the message's full name. It is consulted at the start
+	// of marshalMessage, before protojson's built-in well-known-type
+	// handling, so it lets applications render a message however they like
+	// (e.g. a Money message as a string, a Decimal losslessly, or an
+	// OpenAPI-style discriminated union for a oneof) without forking the
+	// encoder. The one exception is google.protobuf.Any, which is always
+	// expanded via Resolver regardless of any registered entry, since
+	// correctly expanding it depends on resolving the wrapped message's own
+	// type rather than the Any wrapper's.
+	Marshalers map[pref.FullName]func(MarshalOptions, pref.Message, io.Writer) error
 }
 
 // Marshal marshals the given proto.Message in the JSON format using options in
 // MarshalOptions. Do not depend on the output being stable. It may change over
 // time across different versions of the program.
 func (o MarshalOptions) Marshal(m proto.Message) ([]byte, error) {
+	return o.MarshalAppend(nil, m)
+}
+
+// MarshalAppend appends the JSON format encoding of m to dst, returning the
+// resulting slice. It follows the same options and stability caveats as
+// Marshal.
+func (o MarshalOptions) MarshalAppend(dst []byte, m proto.Message) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	_, err := o.MarshalTo(buf, m)
+	return buf.Bytes(), err
+}
+
+// MarshalTo writes the JSON format encoding of m to w using options in
+// MarshalOptions, returning the number of bytes written. Unlike Marshal, it
+// does not build the entire encoding in memory first: o.encoder flushes each
+// object, array, and scalar to w as it is produced, so a large message (a
+// multi-megabyte Any payload, a log record with many repeated fields) can be
+// streamed directly into an http.ResponseWriter, a gzip.Writer, or a
+// bufio.Writer without an intermediate allocation.
+//
+// Because output is flushed incrementally, a caller that receives a non-nil
+// error may still have had some bytes written to w before the error occurred.
+// As with Marshal, the AllowPartial/IsInitialized check is performed last, so
+// the returned count reflects a complete, valid encoding whenever the error is
+// nil or solely a missing-required-fields error.
+func (o MarshalOptions) MarshalTo(w io.Writer, m proto.Message) (int64, error) {
+	if o.Deterministic {
+		if o.EmitUnpopulated {
+			return 0, errors.New("protojson: Deterministic and EmitUnpopulated cannot be used together")
+		}
+		o.Indent = ""
+	}
 	var err error
-	o.encoder, err = json.NewEncoder(o.Indent)
+	o.encoder, err = json.NewEncoder(w, o.Indent)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	if o.Resolver == nil {
 		o.Resolver = protoregistry.GlobalTypes
@@ -87,17 +158,43 @@ func (o MarshalOptions) Marshal(m proto.Message) ([]byte, error) {
 
 	err = o.marshalMessage(m.ProtoReflect())
 	if err != nil {
-		return nil, err
+		return o.encoder.Written(), err
+	}
+	if err := o.encoder.Err(); err != nil {
+		return o.encoder.Written(), err
 	}
 	if o.AllowPartial {
-		return o.encoder.Bytes(), nil
+		return o.encoder.Written(), nil
 	}
-	return o.encoder.Bytes(), proto.IsInitialized(m)
+	return o.encoder.Written(), proto.IsInitialized(m)
 }
 
+// httpBodyMessageFullName is the full name of google.api.HttpBody, handled
+// as a special case below since, unlike the google.protobuf.* well-known
+// types, it lives outside this module and callers cannot rely on
+// isCustomType recognizing it.
+const httpBodyMessageFullName pref.FullName = "google.api.HttpBody"
+
+// anyMessageFullName is the full name of google.protobuf.Any. A registered
+// Marshalers entry never takes over for it; see MarshalOptions.Marshalers.
+const anyMessageFullName pref.FullName = "google.protobuf.Any"
+
 // marshalMessage marshals the given protoreflect.Message.
 func (o MarshalOptions) marshalMessage(m pref.Message) error {
-	if isCustomType(m.Descriptor().FullName()) {
+	name := m.Descriptor().FullName()
+	if name != anyMessageFullName {
+		if fn, ok := o.Marshalers[name]; ok {
+			var buf bytes.Buffer
+			if err := fn(o, m, &buf); err != nil {
+				return err
+			}
+			return o.encoder.WriteRaw(buf.Bytes())
+		}
+	}
+	if name == httpBodyMessageFullName {
+		return o.marshalHTTPBody(m)
+	}
+	if isCustomType(name) {
 		return o.marshalCustomType(m)
 	}
 
@@ -110,6 +207,37 @@ func (o MarshalOptions) marshalMessage(m pref.Message) error {
 	return nil
 }
 
+// marshalHTTPBody marshals a google.api.HttpBody message. Rather than the
+// default {"contentType":...,"data":"<base64>"} rendering, it emits the data
+// field verbatim as the JSON value so that gRPC-transcoded responses (PDFs,
+// images, streamed JSON) round-trip without being mangled into a wrapper
+// object. content_type decides how data is rendered: a JSON content type is
+// spliced in unescaped, a text content type is emitted as a JSON string, and
+// anything else falls back to base64 so the output is always valid JSON.
+func (o MarshalOptions) marshalHTTPBody(m pref.Message) error {
+	fields := m.Descriptor().Fields()
+	contentTypeFD := fields.ByName("content_type")
+	dataFD := fields.ByName("data")
+	if contentTypeFD == nil || dataFD == nil {
+		return errors.New("invalid google.api.HttpBody: missing content_type or data field")
+	}
+
+	contentType := m.Get(contentTypeFD).String()
+	if contentType == "" {
+		contentType = o.HTTPBodyContentType
+	}
+	data := m.Get(dataFD).Bytes()
+
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		return o.encoder.WriteRaw(data)
+	case strings.HasPrefix(contentType, "text/"):
+		return o.encoder.WriteString(string(data))
+	default:
+		return o.encoder.WriteString(base64.StdEncoding.EncodeToString(data))
+	}
+}
+
 // marshalFields marshals the fields in the given protoreflect.Message.
 func (o MarshalOptions) marshalFields(m pref.Message) error {
 	messageDesc := m.Descriptor()
@@ -117,10 +245,27 @@ func (o MarshalOptions) marshalFields(m pref.Message) error {
 		return errors.New("no support for proto1 MessageSets")
 	}
 
-	// Marshal out known fields.
+	// Marshal out known fields. In the common, non-Deterministic case, walk
+	// fieldDescs directly to avoid allocating and copying out a parallel
+	// slice for every message encoded.
 	fieldDescs := messageDesc.Fields()
+	var sorted []pref.FieldDescriptor
+	if o.Deterministic {
+		// Declaration order can shift as a .proto file is edited over time;
+		// field-number order does not, so it is what Deterministic promises.
+		sorted = make([]pref.FieldDescriptor, fieldDescs.Len())
+		for i := range sorted {
+			sorted[i] = fieldDescs.Get(i)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Number() < sorted[j].Number()
+		})
+	}
 	for i := 0; i < fieldDescs.Len(); i++ {
 		fd := fieldDescs.Get(i)
+		if sorted != nil {
+			fd = sorted[i]
+		}
 		val := m.Get(fd)
 		if !m.Has(fd) {
 			if !o.EmitUnpopulated || fd.ContainingOneof() != nil {
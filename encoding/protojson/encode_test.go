@@ -0,0 +1,44 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protojson_test
+
+import (
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TestMarshalersOverrideTimestamp demonstrates using MarshalOptions.Marshalers
+// to override the JSON representation of google.protobuf.Timestamp, emitting
+// Unix epoch milliseconds instead of the RFC 3339 string protojson produces
+// by default.
+func TestMarshalersOverrideTimestamp(t *testing.T) {
+	ts := timestamppb.New(time.Unix(1257894000, 0))
+	opts := protojson.MarshalOptions{
+		Marshalers: map[protoreflect.FullName]func(protojson.MarshalOptions, protoreflect.Message, io.Writer) error{
+			"google.protobuf.Timestamp": func(_ protojson.MarshalOptions, m protoreflect.Message, w io.Writer) error {
+				fields := m.Descriptor().Fields()
+				seconds := m.Get(fields.ByName("seconds")).Int()
+				nanos := m.Get(fields.ByName("nanos")).Int()
+				millis := seconds*1000 + nanos/1e6
+				_, err := io.WriteString(w, strconv.FormatInt(millis, 10))
+				return err
+			},
+		},
+	}
+
+	got, err := opts.Marshal(ts)
+	if err != nil {
+		t.Fatalf("Marshal() returned error: %v", err)
+	}
+	if want := "1257894000000"; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
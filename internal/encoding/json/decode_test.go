@@ -0,0 +1,49 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package json
+
+import "testing"
+
+func TestReadValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `123`, want: `123`},
+		{in: `   123`, want: `123`},
+		{in: "\n\t 123", want: `123`},
+		{in: `"a string"`, want: `"a string"`},
+		{in: `  "a string"`, want: `"a string"`},
+		{in: `{"a":1,"b":[2,3]}`, want: `{"a":1,"b":[2,3]}`},
+		{in: `  {"a":1,"b":[2,3]}  `, want: `{"a":1,"b":[2,3]}`},
+		{in: `[1,{"a":2},3]`, want: `[1,{"a":2},3]`},
+		{in: `null`, want: `null`},
+	}
+	for _, tt := range tests {
+		d := NewDecoder([]byte(tt.in))
+		got, err := d.ReadValue()
+		if err != nil {
+			t.Errorf("ReadValue(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("ReadValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadValueThenEOF(t *testing.T) {
+	d := NewDecoder([]byte(`  {"a":1}  `))
+	if _, err := d.ReadValue(); err != nil {
+		t.Fatalf("ReadValue() returned error: %v", err)
+	}
+	tok, err := d.Read()
+	if err != nil {
+		t.Fatalf("Read() after ReadValue() returned error: %v", err)
+	}
+	if tok.Kind() != EOF {
+		t.Errorf("Read() after ReadValue() = %v, want EOF", tok.Kind())
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package json provides a low-level encoder for the protobuf JSON format,
+// used by encoding/protojson.
+package json
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/internal/errors"
+)
+
+// frame tracks the state of one currently open Object or Array: whether it
+// is an Object (so that a member's comma/newline is emitted once, by
+// WriteName, rather than once per name and once per value) and whether a
+// member/element has already been written (so the next one needs a leading
+// comma).
+type frame struct {
+	isObject  bool
+	hasMember bool
+}
+
+// Encoder encodes the protobuf JSON format. Unlike a []byte-backed encoder,
+// it flushes each object, array, and scalar to the underlying io.Writer as
+// soon as it is produced rather than building the entire encoding in memory
+// first, so a large message can be streamed directly into an io.Writer such
+// as an http.ResponseWriter, a gzip.Writer, or a bufio.Writer.
+type Encoder struct {
+	w       io.Writer
+	indent  string
+	buf     bytes.Buffer
+	written int64
+	err     error
+	stack   []frame
+}
+
+// NewEncoder constructs an Encoder that writes JSON to w. If indent is a
+// non-empty string, it causes entries for an Object or Array to be preceded
+// by the indent and trailed by a newline. It may only be composed of space
+// or tab characters.
+func NewEncoder(w io.Writer, indent string) (*Encoder, error) {
+	if len(indent) > 0 && strings.Trim(indent, " \t") != "" {
+		return nil, errors.New("indent may only be composed of space or tab characters")
+	}
+	return &Encoder{w: w, indent: indent}, nil
+}
+
+// Written reports the number of bytes flushed to the underlying io.Writer
+// so far.
+func (e *Encoder) Written() int64 {
+	return e.written
+}
+
+// Err returns the first error the Encoder encountered, if any: an error
+// returned by the underlying io.Writer's Write method, or invalid UTF-8
+// passed to WriteName or WriteString. Once set, every subsequent method becomes a no-op, so
+// callers that stream output (e.g. MarshalTo) must check Err after encoding
+// completes rather than relying on a write failure to surface through some
+// other method's return value.
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+// flush writes the contents of the internal buffer to w, tracking the
+// number of bytes written. It is called after every public method so that
+// output reaches w at the end of each object, array, or scalar rather than
+// only once the full message has been encoded.
+func (e *Encoder) flush() {
+	if e.err != nil || e.buf.Len() == 0 {
+		return
+	}
+	n, err := e.w.Write(e.buf.Bytes())
+	e.written += int64(n)
+	e.buf.Reset()
+	if err != nil {
+		e.err = err
+	}
+}
+
+func (e *Encoder) newline(depth int) {
+	if e.indent == "" {
+		return
+	}
+	e.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString(e.indent)
+	}
+}
+
+// prefixValue emits the comma and/or newline needed before writing a value:
+// a new element in an Array, or a top-level value. It is a no-op directly
+// inside an Object, where prefixName already accounted for the comma when
+// the member's name was written.
+func (e *Encoder) prefixValue() {
+	if len(e.stack) == 0 {
+		return
+	}
+	top := &e.stack[len(e.stack)-1]
+	if top.isObject {
+		return
+	}
+	if top.hasMember {
+		e.buf.WriteByte(',')
+	} else {
+		top.hasMember = true
+	}
+	e.newline(len(e.stack))
+}
+
+// prefixName emits the comma and/or newline needed before writing an
+// Object member's name.
+func (e *Encoder) prefixName() {
+	top := &e.stack[len(e.stack)-1]
+	if top.hasMember {
+		e.buf.WriteByte(',')
+	} else {
+		top.hasMember = true
+	}
+	e.newline(len(e.stack))
+}
+
+// StartObject writes out the '{' symbol.
+func (e *Encoder) StartObject() {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	e.buf.WriteByte('{')
+	e.stack = append(e.stack, frame{isObject: true})
+	e.flush()
+}
+
+// EndObject writes out the '}' symbol.
+func (e *Encoder) EndObject() {
+	if e.err != nil {
+		return
+	}
+	f := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	if f.hasMember {
+		e.newline(len(e.stack))
+	}
+	e.buf.WriteByte('}')
+	e.flush()
+}
+
+// StartArray writes out the '[' symbol.
+func (e *Encoder) StartArray() {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	e.buf.WriteByte('[')
+	e.stack = append(e.stack, frame{isObject: false})
+	e.flush()
+}
+
+// EndArray writes out the ']' symbol.
+func (e *Encoder) EndArray() {
+	if e.err != nil {
+		return
+	}
+	f := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	if f.hasMember {
+		e.newline(len(e.stack))
+	}
+	e.buf.WriteByte(']')
+	e.flush()
+}
+
+// WriteName writes out the given string for an object field name and
+// returns an error if it contains invalid UTF-8, which is not allowed in
+// protobuf JSON field names.
+func (e *Encoder) WriteName(s string) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.prefixName()
+	if err := e.writeQuotedString(s); err != nil {
+		e.err = err
+		return err
+	}
+	e.buf.WriteByte(':')
+	if e.indent != "" {
+		e.buf.WriteByte(' ')
+	}
+	e.flush()
+	return e.err
+}
+
+// WriteBool writes out the given boolean value.
+func (e *Encoder) WriteBool(b bool) {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	if b {
+		e.buf.WriteString("true")
+	} else {
+		e.buf.WriteString("false")
+	}
+	e.flush()
+}
+
+// WriteString writes out the given string in a JSON string value. Returns
+// an error if s contains invalid UTF-8.
+func (e *Encoder) WriteString(s string) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.prefixValue()
+	if err := e.writeQuotedString(s); err != nil {
+		e.err = err
+		return err
+	}
+	e.flush()
+	return e.err
+}
+
+// Sentinel error used for indicating invalid UTF-8.
+var errInvalidUTF8 = errors.New("invalid UTF-8")
+
+// writeQuotedString writes s to e.buf as a double-quoted, escaped JSON
+// string. This deliberately does not reuse encoding/json's string
+// marshaling, which HTML-escapes '<', '>', and '&' by default - behavior
+// that is wrong for protobuf JSON, which is not assumed to be embedded in
+// HTML.
+func (e *Encoder) writeQuotedString(s string) error {
+	e.buf.WriteByte('"')
+	i := indexNeedEscapeInString(s)
+	s, chunk := s[i:], s[:i]
+	e.buf.WriteString(chunk)
+	for len(s) > 0 {
+		switch r, n := utf8.DecodeRuneInString(s); {
+		case r == utf8.RuneError && n == 1:
+			return errInvalidUTF8
+		case r < ' ' || r == '"' || r == '\\':
+			e.buf.WriteByte('\\')
+			switch r {
+			case '"', '\\':
+				e.buf.WriteByte(byte(r))
+			case '\b':
+				e.buf.WriteByte('b')
+			case '\f':
+				e.buf.WriteByte('f')
+			case '\n':
+				e.buf.WriteByte('n')
+			case '\r':
+				e.buf.WriteByte('r')
+			case '\t':
+				e.buf.WriteByte('t')
+			default:
+				e.buf.WriteByte('u')
+				e.buf.WriteString("0000"[1+(bits.Len32(uint32(r))-1)/4:])
+				e.buf.WriteString(strconv.FormatUint(uint64(r), 16))
+			}
+			s = s[n:]
+		default:
+			i := indexNeedEscapeInString(s[n:])
+			s, chunk = s[n+i:], s[:n+i]
+			e.buf.WriteString(chunk)
+		}
+	}
+	e.buf.WriteByte('"')
+	return nil
+}
+
+// indexNeedEscapeInString returns the index of the character that needs
+// escaping. If no characters need escaping, this returns the input length.
+func indexNeedEscapeInString(s string) int {
+	for i, r := range s {
+		if r < ' ' || r == '\\' || r == '"' || r == utf8.RuneError {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// WriteInt writes out the given signed integer value.
+func (e *Encoder) WriteInt(n int64) {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	e.buf.WriteString(strconv.FormatInt(n, 10))
+	e.flush()
+}
+
+// WriteUint writes out the given unsigned integer value.
+func (e *Encoder) WriteUint(n uint64) {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	e.buf.WriteString(strconv.FormatUint(n, 10))
+	e.flush()
+}
+
+// WriteFloat writes out the given float value for the given bitSize.
+func (e *Encoder) WriteFloat(n float64, bitSize int) {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	switch {
+	case math.IsNaN(n):
+		e.buf.WriteString(`"NaN"`)
+	case math.IsInf(n, +1):
+		e.buf.WriteString(`"Infinity"`)
+	case math.IsInf(n, -1):
+		e.buf.WriteString(`"-Infinity"`)
+	default:
+		e.buf.WriteString(strconv.FormatFloat(n, 'g', -1, bitSize))
+	}
+	e.flush()
+}
+
+// WriteNull writes out the null value.
+func (e *Encoder) WriteNull() {
+	if e.err != nil {
+		return
+	}
+	e.prefixValue()
+	e.buf.WriteString("null")
+	e.flush()
+}
+
+// WriteRaw writes b out verbatim as a single value, without quoting or
+// escaping it. Callers are responsible for b being valid JSON; this is used
+// to splice in already-encoded JSON, such as a MarshalOptions.Marshalers
+// hook's output or a google.api.HttpBody payload that must round-trip
+// unmodified.
+func (e *Encoder) WriteRaw(b []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.prefixValue()
+	e.buf.Write(b)
+	e.flush()
+	return e.err
+}
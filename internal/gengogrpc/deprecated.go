@@ -0,0 +1,31 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gengogrpc
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/internal/errors"
+)
+
+// deprecatedPluginsGRPCValue is the value of protoc-gen-go's legacy
+// 'plugins' parameter (invoked as '--go_out=plugins=grpc:...') that used to
+// select inline gRPC service-stub generation, before it was split out into
+// the standalone protoc-gen-go-grpc plugin.
+const deprecatedPluginsGRPCValue = "grpc"
+
+// CheckDeprecatedPluginsParam reports a descriptive error if plugins, the
+// raw value of a 'plugins' parameter passed to protoc-gen-go or
+// protoc-gen-go-grpc, still requests the removed inline grpc mode. This
+// lets a build file that has not yet been migrated off 'plugins=grpc' fail
+// with guidance instead of either plugin silently ignoring the parameter.
+func CheckDeprecatedPluginsParam(plugins string) error {
+	for _, p := range strings.Split(plugins, ",") {
+		if p == deprecatedPluginsGRPCValue {
+			return errors.New("the 'plugins=grpc' parameter is no longer supported; generate gRPC service stubs by running protoc with --go-grpc_out instead")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gengogrpc
+
+import "testing"
+
+func TestCheckDeprecatedPluginsParam(t *testing.T) {
+	tests := []struct {
+		plugins string
+		wantErr bool
+	}{
+		{plugins: "", wantErr: false},
+		{plugins: "grpc", wantErr: true},
+		{plugins: "paths=source_relative,grpc", wantErr: true},
+		{plugins: "paths=source_relative", wantErr: false},
+	}
+	for _, tt := range tests {
+		err := CheckDeprecatedPluginsParam(tt.plugins)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("CheckDeprecatedPluginsParam(%q) = %v, wantErr %v", tt.plugins, err, tt.wantErr)
+		}
+	}
+}
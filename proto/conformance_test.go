@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proto_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/internal/testprotos/conformance"
+	"google.golang.org/protobuf/proto"
+)
+
+// See encoding/protojson's conformance_test.go for the protocol this
+// trampoline and TestConformance speak; this is the parallel runner for the
+// wire (binary) format rather than JSON.
+func init() {
+	if os.Getenv("RUN_AS_CONFORMANCE") != "" {
+		if err := runConformance(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+}
+
+func runConformance(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		req, err := readConformanceRequest(br)
+		if err == io.EOF {
+			return bw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeConformanceResponse(bw, handleConformanceRequest(req)); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+func readConformanceRequest(r io.Reader) (*conformance.ConformanceRequest, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.LittleEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	req := &conformance.ConformanceRequest{}
+	if err := proto.Unmarshal(buf, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func writeConformanceResponse(w io.Writer, resp *conformance.ConformanceResponse) error {
+	buf, err := proto.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	var lenBuf [4]byte
+	binary.LittleEndian.PutUint32(lenBuf[:], uint32(len(buf)))
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func handleConformanceRequest(req *conformance.ConformanceRequest) *conformance.ConformanceResponse {
+	msg := &conformance.TestAllTypesProto3{}
+	switch p := req.Payload.(type) {
+	case *conformance.ConformanceRequest_ProtobufPayload:
+		if err := proto.Unmarshal(p.ProtobufPayload, msg); err != nil {
+			return &conformance.ConformanceResponse{
+				Result: &conformance.ConformanceResponse_ParseError{ParseError: err.Error()},
+			}
+		}
+	default:
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_Skipped{Skipped: "unsupported input format"},
+		}
+	}
+
+	if req.RequestedOutputFormat != conformance.WireFormat_PROTOBUF {
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_Skipped{Skipped: "unsupported output format"},
+		}
+	}
+
+	out, err := proto.MarshalOptions{AllowPartial: false}.Marshal(msg)
+	if err != nil {
+		return &conformance.ConformanceResponse{
+			Result: &conformance.ConformanceResponse_SerializeError{SerializeError: err.Error()},
+		}
+	}
+	return &conformance.ConformanceResponse{
+		Result: &conformance.ConformanceResponse_ProtobufPayload{ProtobufPayload: out},
+	}
+}
+
+// TestConformance runs the core proto package's wire-format Marshal/Unmarshal
+// against the upstream protobuf conformance test suite, the binary-format
+// counterpart to encoding/protojson's TestConformance.
+func TestConformance(t *testing.T) {
+	runner, err := exec.LookPath("conformance-test-runner")
+	if err != nil {
+		t.Skip("conformance-test-runner not found in PATH; skipping")
+	}
+
+	failureList := filepath.Join("testdata", "conformance_failing.txt")
+	cmd := exec.Command(runner,
+		"--failure_list", failureList,
+		os.Args[0])
+	cmd.Env = append(os.Environ(), "RUN_AS_CONFORMANCE=1")
+	out, err := cmd.CombinedOutput()
+	if len(out) > 0 {
+		t.Log(string(out))
+	}
+	if err != nil {
+		t.Fatalf("conformance-test-runner: %v", err)
+	}
+}